@@ -0,0 +1,48 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// AuditEntry records a single API-driven mutation of the scheduler
+// config.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Scheduler string    `json:"scheduler"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// AuditLog is an append-only, in-memory record of every write the API has
+// made. It is safe for concurrent use.
+type AuditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+// NewAuditLog returns an empty AuditLog.
+func NewAuditLog() *AuditLog {
+	return &AuditLog{}
+}
+
+// Record appends entry to the log, stamping it with the current time if
+// the caller didn't already set one.
+func (a *AuditLog) Record(entry AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, entry)
+}
+
+// Entries returns a copy of every entry recorded so far, oldest first.
+func (a *AuditLog) Entries() []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]AuditEntry, len(a.entries))
+	copy(out, a.entries)
+	return out
+}
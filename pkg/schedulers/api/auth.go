@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Authenticator gates write access to the scheduler API. A real
+// deployment plugs in the existing GitHub-app auth; tests and local runs
+// can use AllowAll.
+type Authenticator interface {
+	// Authenticate inspects r and returns the identity of the caller, or
+	// an error if the request is not authorized to make changes.
+	Authenticate(r *http.Request) (actor string, err error)
+}
+
+// AllowAll authenticates every request as "anonymous". It exists for
+// local development and tests; production wiring should supply an
+// Authenticator backed by the GitHub app's own auth.
+type AllowAll struct{}
+
+// Authenticate always succeeds.
+func (AllowAll) Authenticate(r *http.Request) (string, error) {
+	return "anonymous", nil
+}
+
+// ErrUnauthorized is returned by an Authenticator when the request carries
+// no usable credentials at all, distinct from credentials that were
+// checked and rejected.
+var ErrUnauthorized = errors.New("no credentials supplied")
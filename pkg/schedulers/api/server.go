@@ -0,0 +1,264 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/client/clientset/versioned"
+	"github.com/jenkins-x/lighthouse/pkg/schedulers"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Server implements http.Handler for the live scheduler config API. It
+// reads from a Store kept up to date by Regenerate, and writes go through
+// Auth and are recorded to Audit.
+type Server struct {
+	Store *Store
+	Auth  Authenticator
+	Audit *AuditLog
+
+	JxClient               versioned.Interface
+	Namespace              string
+	TeamSchedulerName      string
+	GitOps                 bool
+	AutoApplyConfigUpdater bool
+	DevEnv                 *jenkinsv1.Environment
+
+	// regenMu serializes Regenerate end to end, including the
+	// loadResourcesExcludingPaused call GenerateProw makes partway
+	// through it. Without it, two concurrent Regenerate calls (e.g. from
+	// updateScheduler and setPaused) could interleave their
+	// lastLookup/lastRepos writes with each other's read of them,
+	// producing a Snapshot whose Config/Plugins came from one request's
+	// merge but whose Schedulers/Repos came from the other's.
+	regenMu sync.Mutex
+	// lastLookup and lastRepos are populated by loadResourcesExcludingPaused
+	// as a side effect of the Regenerate that invoked it. Both are only
+	// ever read or written while regenMu is held.
+	lastLookup map[string]*jenkinsv1.Scheduler
+	lastRepos  map[string]*RepoConfig
+}
+
+// NewServer returns a Server backed by store, gating writes with auth.
+func NewServer(store *Store, auth Authenticator, jxClient versioned.Interface, namespace string, teamSchedulerName string, gitOps bool, autoApplyConfigUpdater bool, devEnv *jenkinsv1.Environment) *Server {
+	if auth == nil {
+		auth = AllowAll{}
+	}
+	return &Server{
+		Store:                  store,
+		Auth:                   auth,
+		Audit:                  NewAuditLog(),
+		JxClient:               jxClient,
+		Namespace:              namespace,
+		TeamSchedulerName:      teamSchedulerName,
+		GitOps:                 gitOps,
+		AutoApplyConfigUpdater: autoApplyConfigUpdater,
+		DevEnv:                 devEnv,
+	}
+}
+
+// ServeHTTP routes requests to the scheduler API's endpoints.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case r.Method == http.MethodGet && path == "/schedulers":
+		s.listSchedulers(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/schedulers/"):
+		s.getScheduler(w, r, strings.TrimPrefix(path, "/schedulers/"))
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/pause"):
+		s.setPaused(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/schedulers/"), "/pause"), true)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/resume"):
+		s.setPaused(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/schedulers/"), "/resume"), false)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/schedulers/"):
+		s.updateScheduler(w, r, strings.TrimPrefix(path, "/schedulers/"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/repos/") && strings.HasSuffix(path, "/effective"):
+		s.effective(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/repos/"), "/effective"))
+	case r.Method == http.MethodGet && path == "/audit":
+		s.listAudit(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) listSchedulers(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.Store.Snapshot()
+	if snapshot == nil {
+		writeJSON(w, http.StatusOK, struct {
+			Digest     string   `json:"digest"`
+			Schedulers []string `json:"schedulers"`
+		}{Schedulers: []string{}})
+		return
+	}
+	names := make([]string, 0, len(snapshot.Schedulers))
+	for name := range snapshot.Schedulers {
+		names = append(names, name)
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Digest     string   `json:"digest"`
+		Schedulers []string `json:"schedulers"`
+	}{Digest: snapshot.Digest, Schedulers: names})
+}
+
+func (s *Server) getScheduler(w http.ResponseWriter, r *http.Request, name string) {
+	snapshot := s.Store.Snapshot()
+	if snapshot == nil {
+		http.Error(w, "no scheduler config has been generated yet", http.StatusServiceUnavailable)
+		return
+	}
+	scheduler, ok := snapshot.Schedulers[name]
+	if !ok {
+		http.Error(w, "scheduler not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, scheduler)
+}
+
+func (s *Server) effective(w http.ResponseWriter, r *http.Request, orgRepo string) {
+	parts := strings.SplitN(orgRepo, "/", 2)
+	if len(parts) != 2 {
+		http.Error(w, "expected /repos/{org}/{repo}/effective", http.StatusBadRequest)
+		return
+	}
+	snapshot := s.Store.Snapshot()
+	if snapshot == nil {
+		http.Error(w, "no scheduler config has been generated yet", http.StatusServiceUnavailable)
+		return
+	}
+	repoConfig, ok := snapshot.Repos[orgRepo]
+	if !ok {
+		http.Error(w, "no effective config recorded for that repo", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, repoConfig)
+}
+
+func (s *Server) listAudit(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Audit.Entries())
+}
+
+func (s *Server) updateScheduler(w http.ResponseWriter, r *http.Request, name string) {
+	actor, err := s.Auth.Authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var spec jenkinsv1.SchedulerSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		http.Error(w, errors.Wrapf(err, "decoding scheduler spec").Error(), http.StatusBadRequest)
+		return
+	}
+	scheduler, err := s.JxClient.JenkinsV1().Schedulers(s.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		http.Error(w, errors.Wrapf(err, "loading scheduler %s", name).Error(), http.StatusNotFound)
+		return
+	}
+	scheduler.Spec = spec
+	if _, err := s.JxClient.JenkinsV1().Schedulers(s.Namespace).Update(scheduler); err != nil {
+		http.Error(w, errors.Wrapf(err, "updating scheduler %s", name).Error(), http.StatusInternalServerError)
+		return
+	}
+	s.Audit.Record(AuditEntry{Actor: actor, Action: "update", Scheduler: name})
+	if err := s.Regenerate(); err != nil {
+		http.Error(w, errors.Wrapf(err, "regenerating prow config").Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Store.Snapshot())
+}
+
+func (s *Server) setPaused(w http.ResponseWriter, r *http.Request, name string, paused bool) {
+	actor, err := s.Auth.Authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	action := "pause"
+	if paused {
+		s.Store.Pause(name)
+	} else {
+		action = "resume"
+		s.Store.Resume(name)
+	}
+	s.Audit.Record(AuditEntry{Actor: actor, Action: action, Scheduler: name})
+	if err := s.Regenerate(); err != nil {
+		http.Error(w, errors.Wrapf(err, "regenerating prow config").Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.Store.Snapshot())
+}
+
+// Regenerate re-runs GenerateProw, excluding any schedulers currently
+// paused in the Store, and records the result as the new Snapshot.
+func (s *Server) Regenerate() error {
+	s.regenMu.Lock()
+	defer s.regenMu.Unlock()
+	cfg, plugs, err := schedulers.GenerateProw(s.GitOps, s.AutoApplyConfigUpdater, s.JxClient, s.Namespace, s.TeamSchedulerName, s.DevEnv, s.loadResourcesExcludingPaused)
+	if err != nil {
+		return err
+	}
+	snapshot := &Snapshot{
+		Schedulers: s.lastLookup,
+		Repos:      s.lastRepos,
+		Config:     cfg,
+		Plugins:    plugs,
+	}
+	snapshot.Digest = Digest(snapshot.Schedulers)
+	s.Store.Record(snapshot)
+	return nil
+}
+
+// loadResourcesExcludingPaused is passed to GenerateProw as its
+// loadSchedulerResourcesFunc hook. Besides filtering out paused
+// schedulers, it resolves oci:// bundle references the same way
+// schedulers.loadSchedulerResources does, and records the lookup and
+// per-repo merged SchedulerSpec it computed so Regenerate can fold them
+// into the Snapshot it records - GenerateProw itself has no way to
+// return that provenance, so the API recomputes it with
+// EffectiveScheduler immediately after loading resources.
+func (s *Server) loadResourcesExcludingPaused(jxClient versioned.Interface, namespace string) (map[string]*jenkinsv1.Scheduler, *jenkinsv1.SourceRepositoryGroupList, *jenkinsv1.SourceRepositoryList, error) {
+	schedulerList, err := jxClient.JenkinsV1().Schedulers(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+	lookup := make(map[string]*jenkinsv1.Scheduler)
+	for _, item := range schedulerList.Items {
+		if s.Store.IsPaused(item.Name) {
+			continue
+		}
+		lookup[item.Name] = item.DeepCopy()
+	}
+	sourceRepoGroups, err := jxClient.JenkinsV1().SourceRepositoryGroups(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "Error finding source repository groups")
+	}
+	sourceRepos, err := jxClient.JenkinsV1().SourceRepositories(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "Error finding source repositories")
+	}
+	if err := schedulers.ResolveBundleRefs(lookup, sourceRepoGroups, sourceRepos); err != nil {
+		return nil, nil, nil, err
+	}
+	s.lastLookup = lookup
+	s.lastRepos = make(map[string]*RepoConfig, len(sourceRepos.Items))
+	for _, sourceRepo := range sourceRepos.Items {
+		spec, _, _, err := schedulers.EffectiveScheduler(s.GitOps, s.AutoApplyConfigUpdater, s.TeamSchedulerName, s.DevEnv, lookup, sourceRepoGroups, sourceRepo)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if spec == nil {
+			continue
+		}
+		key := sourceRepo.Spec.Org + "/" + sourceRepo.Spec.Repo
+		s.lastRepos[key] = &RepoConfig{Org: sourceRepo.Spec.Org, Repo: sourceRepo.Spec.Repo, SchedulerSpec: spec}
+	}
+	return lookup, sourceRepoGroups, sourceRepos, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,122 @@
+// Package api exposes an HTTP API for introspecting and mutating the
+// generated scheduler/prow config at runtime, in the spirit of a
+// scheduling-server's config forwarding API: GenerateProw's output is
+// written to an in-memory Store the API reads from, so read requests
+// never block on a fresh merge.
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/lighthouse/pkg/prow/config"
+	"github.com/jenkins-x/lighthouse/pkg/prow/plugins"
+)
+
+// RepoConfig is the merged SchedulerSpec a single repo received in the
+// last regeneration. It does not hold the repo's prow config fragment.
+type RepoConfig struct {
+	Org           string
+	Repo          string
+	SchedulerSpec *jenkinsv1.SchedulerSpec
+}
+
+// Snapshot is everything a single GenerateProw run produced, kept in the
+// Store for the API to serve without recomputing it.
+type Snapshot struct {
+	Digest     string
+	Schedulers map[string]*jenkinsv1.Scheduler
+	Repos      map[string]*RepoConfig
+	Config     *config.Config
+	Plugins    *plugins.Configuration
+}
+
+// Store holds the most recent Snapshot plus the set of schedulers
+// temporarily paused via the API. It is safe for concurrent use by the
+// HTTP handlers and by whatever writes new Snapshots after a
+// regeneration.
+type Store struct {
+	mu       sync.RWMutex
+	snapshot *Snapshot
+	paused   map[string]bool
+}
+
+// NewStore returns an empty Store.
+func NewStore() *Store {
+	return &Store{paused: make(map[string]bool)}
+}
+
+// Record replaces the current Snapshot, invalidating anything cached
+// against the previous digest.
+func (s *Store) Record(snapshot *Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = snapshot
+}
+
+// Snapshot returns the most recently recorded Snapshot, or nil if none
+// has been recorded yet.
+func (s *Store) Snapshot() *Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot
+}
+
+// Pause excludes name from future regenerations until Resume is called.
+func (s *Store) Pause(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused[name] = true
+}
+
+// Resume re-includes a previously paused scheduler in future
+// regenerations.
+func (s *Store) Resume(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.paused, name)
+}
+
+// IsPaused reports whether name is currently excluded from merging.
+func (s *Store) IsPaused(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.paused[name]
+}
+
+// PausedNames returns a snapshot of every currently paused scheduler
+// name.
+func (s *Store) PausedNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.paused))
+	for name := range s.paused {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Digest returns a hash of the given scheduler set's names and resource
+// versions, used to
+// detect whether a regeneration actually changed anything a cache might
+// be keyed on.
+func Digest(schedulers map[string]*jenkinsv1.Scheduler) string {
+	names := make([]string, 0, len(schedulers))
+	for name := range schedulers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		scheduler := schedulers[name]
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(scheduler.ResourceVersion))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
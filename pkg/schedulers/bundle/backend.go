@@ -0,0 +1,171 @@
+package bundle
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// PullOptions customizes a Pull call.
+type PullOptions struct {
+	// Alias, if set, is the name the caller intends to install the
+	// pulled bundle's scheduler under, letting several bundles that
+	// share the same upstream name coexist in one cluster.
+	Alias string
+}
+
+// Backend distributes scheduler bundles, mirroring a Docker-style plugin
+// backend's Pull/Push/Upgrade/Inspect/Privileges surface.
+type Backend interface {
+	// Pull fetches the bundle referenced by ref (e.g.
+	// "oci://registry/name@sha256:...") and verifies its digest.
+	Pull(ctx context.Context, ref string, opts PullOptions) (*Bundle, error)
+	// Push publishes bundle under ref.
+	Push(ctx context.Context, ref string, bundle *Bundle) error
+	// Inspect returns the locally installed bundle registered under
+	// name, regardless of whether it is enabled.
+	Inspect(name string) (*Bundle, error)
+	// List returns every bundle installed locally.
+	List() ([]*Bundle, error)
+	// Enable marks an installed bundle as usable by resolvers.
+	Enable(name string) error
+	// Disable marks an installed bundle as unusable without removing it.
+	Disable(name string) error
+	// Upgrade re-pulls ref's name at its latest digest.
+	Upgrade(ctx context.Context, ref string) (*Bundle, error)
+	// Privileges reports the prow features ref's bundle would grant,
+	// without installing it.
+	Privileges(ctx context.Context, ref string) ([]Privilege, error)
+}
+
+// installed tracks one locally known bundle and whether it is enabled.
+type installed struct {
+	bundle  *Bundle
+	enabled bool
+}
+
+// MemoryBackend is an in-memory Backend, used for local development and
+// tests and as the default until a real OCI registry client is wired in.
+type MemoryBackend struct {
+	mu      sync.RWMutex
+	bundles map[string]*installed
+}
+
+// NewMemoryBackend returns an empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{bundles: make(map[string]*installed)}
+}
+
+// Seed installs bundle directly, as if it had already been pulled. It is
+// intended for tests that need a backend pre-populated with known
+// bundles.
+func (m *MemoryBackend) Seed(bundle *Bundle) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bundles[bundle.Name] = &installed{bundle: bundle, enabled: true}
+}
+
+func (m *MemoryBackend) Pull(ctx context.Context, ref string, opts PullOptions) (*Bundle, error) {
+	_, name, digest, ok := ParseRef(ref)
+	if !ok {
+		return nil, errors.Errorf("%s is not a valid bundle reference", ref)
+	}
+	m.mu.RLock()
+	entry, found := m.bundles[name]
+	m.mu.RUnlock()
+	if !found {
+		return nil, errors.Errorf("no bundle named %s is known to this backend", name)
+	}
+	if !entry.enabled {
+		return nil, errors.Errorf("bundle %s is disabled", name)
+	}
+	if digest != "" && entry.bundle.Digest != digest {
+		return nil, errors.Errorf("bundle %s resolved to digest %s, expected %s", name, entry.bundle.Digest, digest)
+	}
+	if opts.Alias != "" {
+		aliased := *entry.bundle
+		aliased.Name = opts.Alias
+		return &aliased, nil
+	}
+	copied := *entry.bundle
+	return &copied, nil
+}
+
+func (m *MemoryBackend) Push(ctx context.Context, ref string, bundle *Bundle) error {
+	_, name, _, ok := ParseRef(ref)
+	if !ok {
+		return errors.Errorf("%s is not a valid bundle reference", ref)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bundles[name] = &installed{bundle: bundle, enabled: true}
+	return nil
+}
+
+func (m *MemoryBackend) Inspect(name string) (*Bundle, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.bundles[name]
+	if !ok {
+		return nil, errors.Errorf("no bundle named %s is installed", name)
+	}
+	return entry.bundle, nil
+}
+
+func (m *MemoryBackend) List() ([]*Bundle, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	bundles := make([]*Bundle, 0, len(m.bundles))
+	for _, entry := range m.bundles {
+		bundles = append(bundles, entry.bundle)
+	}
+	return bundles, nil
+}
+
+func (m *MemoryBackend) Enable(name string) error {
+	return m.setEnabled(name, true)
+}
+
+func (m *MemoryBackend) Disable(name string) error {
+	return m.setEnabled(name, false)
+}
+
+func (m *MemoryBackend) setEnabled(name string, enabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.bundles[name]
+	if !ok {
+		return errors.Errorf("no bundle named %s is installed", name)
+	}
+	entry.enabled = enabled
+	return nil
+}
+
+func (m *MemoryBackend) Upgrade(ctx context.Context, ref string) (*Bundle, error) {
+	registry, name, _, ok := ParseRef(ref)
+	if !ok {
+		return nil, errors.Errorf("%s is not a valid bundle reference", ref)
+	}
+	// Upgrade's whole point is moving off ref's pinned digest onto
+	// whatever is newest, so the digest component - if any - is
+	// discarded before pulling: Pull itself would otherwise reject the
+	// now-stale digest ref names, exactly the case Upgrade exists to
+	// handle. With no upstream registry client, the latest locally
+	// installed bundle for name is already the newest version this
+	// backend knows about, so this is just an undigested Pull - which
+	// also means a disabled bundle can't be "upgraded" back into use.
+	return m.Pull(ctx, refPrefix+registry+"/"+name, PullOptions{})
+}
+
+func (m *MemoryBackend) Privileges(ctx context.Context, ref string) ([]Privilege, error) {
+	_, name, _, ok := ParseRef(ref)
+	if !ok {
+		return nil, errors.Errorf("%s is not a valid bundle reference", ref)
+	}
+	bundle, err := m.Inspect(name)
+	if err != nil {
+		return nil, err
+	}
+	return bundle.Privileges, nil
+}
@@ -0,0 +1,81 @@
+// Package bundle distributes reusable scheduler specs ("scheduler
+// bundles") across clusters through a content-addressable, OCI-compatible
+// registry, so a repo's scheduler can be pinned by digest rather than by
+// name and reproduced exactly wherever it is installed.
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/pkg/errors"
+)
+
+// Privilege is a prow feature a bundle's scheduler requires, surfaced so
+// an operator can approve it before install.
+type Privilege string
+
+// The set of privileges a bundle's scheduler spec can require.
+const (
+	PrivilegeBranchProtection Privilege = "branch-protection"
+	PrivilegeConfigUpdater    Privilege = "config-updater"
+	PrivilegeExternalPlugins  Privilege = "external-plugins"
+)
+
+// Bundle is an immutable manifest referencing a single jenkinsv1.Scheduler
+// by content digest.
+type Bundle struct {
+	Name       string
+	Digest     string
+	Scheduler  *jenkinsv1.Scheduler
+	Privileges []Privilege
+}
+
+// ComputeDigest returns the sha256 content digest of scheduler's spec, in
+// the "sha256:<hex>" form used in bundle references.
+func ComputeDigest(scheduler *jenkinsv1.Scheduler) (string, error) {
+	// Only the spec is content-addressed: metadata like resourceVersion
+	// or annotations would otherwise make the same logical scheduler
+	// hash differently between clusters.
+	raw, err := json.Marshal(scheduler.Spec)
+	if err != nil {
+		return "", errors.Wrapf(err, "marshaling scheduler spec")
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// computePrivileges derives the set of prow features a scheduler spec
+// would grant, so operators can review them before approving an install.
+// It is necessarily conservative: it only flags the fields GenerateProw
+// is known to act on today.
+func computePrivileges(spec *jenkinsv1.SchedulerSpec) []Privilege {
+	var privileges []Privilege
+	if spec.Policy != nil {
+		privileges = append(privileges, PrivilegeBranchProtection)
+	}
+	if spec.ConfigUpdater != nil {
+		privileges = append(privileges, PrivilegeConfigUpdater)
+	}
+	if spec.Plugins != nil && len(spec.Plugins.Items) > 0 {
+		privileges = append(privileges, PrivilegeExternalPlugins)
+	}
+	return privileges
+}
+
+// NewBundle builds a Bundle from name and scheduler, computing its digest
+// and privileges.
+func NewBundle(name string, scheduler *jenkinsv1.Scheduler) (*Bundle, error) {
+	digest, err := ComputeDigest(scheduler)
+	if err != nil {
+		return nil, err
+	}
+	return &Bundle{
+		Name:       name,
+		Digest:     digest,
+		Scheduler:  scheduler,
+		Privileges: computePrivileges(&scheduler.Spec),
+	}, nil
+}
@@ -0,0 +1,45 @@
+package bundle
+
+import (
+	"context"
+	"testing"
+
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+)
+
+func newTestBundle(t *testing.T, name string) *Bundle {
+	t.Helper()
+	b, err := NewBundle(name, &jenkinsv1.Scheduler{Spec: jenkinsv1.SchedulerSpec{}})
+	if err != nil {
+		t.Fatalf("NewBundle: %v", err)
+	}
+	return b
+}
+
+func TestMemoryBackendPullRejectsDigestMismatch(t *testing.T) {
+	backend := NewMemoryBackend()
+	backend.Seed(newTestBundle(t, "my-bundle"))
+
+	if _, err := backend.Pull(context.Background(), "oci://registry/my-bundle@sha256:deadbeef", PullOptions{}); err == nil {
+		t.Fatal("Pull with a mismatched digest should have failed")
+	}
+}
+
+func TestMemoryBackendPullRejectsDisabledBundle(t *testing.T) {
+	backend := NewMemoryBackend()
+	backend.Seed(newTestBundle(t, "my-bundle"))
+
+	if err := backend.Disable("my-bundle"); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if _, err := backend.Pull(context.Background(), "oci://registry/my-bundle", PullOptions{}); err == nil {
+		t.Fatal("Pull should reject a disabled bundle")
+	}
+
+	if err := backend.Enable("my-bundle"); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	if _, err := backend.Pull(context.Background(), "oci://registry/my-bundle", PullOptions{}); err != nil {
+		t.Fatalf("Pull should succeed once the bundle is re-enabled: %v", err)
+	}
+}
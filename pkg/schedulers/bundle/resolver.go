@@ -0,0 +1,62 @@
+package bundle
+
+import (
+	"context"
+	"strings"
+
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/pkg/errors"
+)
+
+// refPrefix marks a SourceRepository/SourceRepositoryGroup scheduler name
+// as a bundle reference rather than a plain jenkinsv1.Scheduler name.
+const refPrefix = "oci://"
+
+// IsRef reports whether name is a bundle reference
+// ("oci://registry/name@sha256:...") rather than a plain scheduler name.
+func IsRef(name string) bool {
+	return strings.HasPrefix(name, refPrefix)
+}
+
+// ParseRef splits a bundle reference into its registry host, bundle name
+// and digest. digest is "" if ref names a bundle without pinning it to a
+// specific digest. ok is false if ref isn't an oci:// reference at all.
+func ParseRef(ref string) (registry, name, digest string, ok bool) {
+	if !IsRef(ref) {
+		return "", "", "", false
+	}
+	rest := strings.TrimPrefix(ref, refPrefix)
+	slash := strings.Index(rest, "/")
+	if slash < 0 {
+		return "", "", "", false
+	}
+	registry, nameAndDigest := rest[:slash], rest[slash+1:]
+	if at := strings.Index(nameAndDigest, "@"); at >= 0 {
+		return registry, nameAndDigest[:at], nameAndDigest[at+1:], true
+	}
+	return registry, nameAndDigest, "", true
+}
+
+// Resolver installs scheduler bundles referenced by
+// SourceRepository.Spec.Scheduler.Name / SourceRepositoryGroup.Spec.Scheduler.Name
+// into a plain jenkinsv1.Scheduler lookup map, so the rest of the
+// scheduler-merging pipeline never has to know bundles exist.
+type Resolver struct {
+	Backend Backend
+}
+
+// NewResolver returns a Resolver backed by backend.
+func NewResolver(backend Backend) *Resolver {
+	return &Resolver{Backend: backend}
+}
+
+// Resolve pulls and digest-verifies the bundle ref points at and returns
+// the key its scheduler should be installed under in a lookup map: alias
+// if a non-empty alias is given, the bundle's own name otherwise.
+func (r *Resolver) Resolve(ctx context.Context, ref string, alias string) (key string, scheduler *jenkinsv1.Scheduler, err error) {
+	bundle, err := r.Backend.Pull(ctx, ref, PullOptions{Alias: alias})
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "pulling scheduler bundle %s", ref)
+	}
+	return bundle.Name, bundle.Scheduler, nil
+}
@@ -0,0 +1,147 @@
+package framework
+
+import (
+	"strings"
+
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/log"
+)
+
+// Names of the built-in plugins, usable in a SchedulerProfile.Enabled
+// list. DefaultEnabled is the set wired up by GenerateProw to preserve
+// its historic, non-pluggable behavior.
+//
+// There is deliberately no built-in LoadResources plugin: GenerateProw's
+// loadSchedulerResourcesFunc parameter already is that extension point
+// (see pkg/schedulers.GenerateProw), so a second, jxClient-shaped one
+// here would just be a second way to do the same thing.
+//
+// MergePluginName has no factory in DefaultRegistry either - merging
+// requires pkg/schedulers.Build, which this package can't import without
+// creating a cycle, so pkg/schedulers registers the build-merge plugin
+// itself before running the pipeline.
+const (
+	ConfigUpdaterPluginName = "config-updater"
+	RepositoryPluginName    = "repository-scheduler"
+	ProjectPluginName       = "project-schedulers"
+	TeamPluginName          = "team-scheduler"
+	MergePluginName         = "build-merge"
+)
+
+// DefaultEnabled lists the built-in plugins in the order GenerateProw has
+// always applied them: config-updater, repository, project, team - each
+// one prepended ahead of the previous, so team ends up least specific -
+// followed by the merge itself.
+var DefaultEnabled = []string{
+	ConfigUpdaterPluginName,
+	RepositoryPluginName,
+	ProjectPluginName,
+	TeamPluginName,
+	MergePluginName,
+}
+
+// DefaultRegistry returns a Registry containing every built-in plugin
+// that doesn't need anything outside this package to construct. Callers
+// that also need the merge plugin (i.e. pkg/schedulers) register it on
+// top of this registry themselves.
+func DefaultRegistry() Registry {
+	return Registry{
+		ConfigUpdaterPluginName: func(map[string]interface{}) (Plugin, error) { return &configUpdaterPlugin{}, nil },
+		RepositoryPluginName:    func(map[string]interface{}) (Plugin, error) { return &repositoryPlugin{}, nil },
+		ProjectPluginName:       func(map[string]interface{}) (Plugin, error) { return &projectPlugin{}, nil },
+		TeamPluginName:          func(map[string]interface{}) (Plugin, error) { return &teamPlugin{}, nil },
+	}
+}
+
+// configUpdaterPlugin reimplements addConfigUpdaterToDevEnv as an
+// ApplyRepository plugin: it prepends a scheduler that wires up the
+// config-updater plugin when the dev environment's source matches the
+// current repo.
+type configUpdaterPlugin struct{}
+
+func (p *configUpdaterPlugin) Name() string { return ConfigUpdaterPluginName }
+
+func (p *configUpdaterPlugin) ApplyRepository(ctx *SchedulerContext) *Status {
+	if !(ctx.GitOps && ctx.AutoApplyConfigUpdater) {
+		return nil
+	}
+	if !strings.Contains(ctx.DevEnv.Spec.Source.URL, ctx.SourceRepo.Spec.Org+"/"+ctx.SourceRepo.Spec.Repo) {
+		return nil
+	}
+	maps := make(map[string]jenkinsv1.ConfigMapSpec)
+	maps["env/prow/config.yaml"] = jenkinsv1.ConfigMapSpec{Name: "config"}
+	maps["env/prow/plugins.yaml"] = jenkinsv1.ConfigMapSpec{Name: "plugins"}
+	environmentUpdaterSpec := &jenkinsv1.SchedulerSpec{
+		ConfigUpdater: &jenkinsv1.ConfigUpdater{Map: maps},
+		Plugins: &jenkinsv1.ReplaceableSliceOfStrings{
+			Items: []string{"config-updater"},
+		},
+	}
+	ctx.Schedulers = append([]*jenkinsv1.SchedulerSpec{environmentUpdaterSpec}, ctx.Schedulers...)
+	return nil
+}
+
+// repositoryPlugin reimplements addRepositoryScheduler as an
+// ApplyRepository plugin.
+type repositoryPlugin struct{}
+
+func (p *repositoryPlugin) Name() string { return RepositoryPluginName }
+
+func (p *repositoryPlugin) ApplyRepository(ctx *SchedulerContext) *Status {
+	name := ctx.SourceRepo.Spec.Scheduler.Name
+	if name == "" {
+		return nil
+	}
+	scheduler := ctx.Lookup[name]
+	if scheduler == nil {
+		log.Logger().Warnf("A scheduler named %s is referenced by repository(%s) but could not be found", name, ctx.SourceRepo.Name)
+		return nil
+	}
+	ctx.Schedulers = append([]*jenkinsv1.SchedulerSpec{&scheduler.Spec}, ctx.Schedulers...)
+	return nil
+}
+
+// projectPlugin reimplements addProjectSchedulers as an ApplyProject
+// plugin.
+type projectPlugin struct{}
+
+func (p *projectPlugin) Name() string { return ProjectPluginName }
+
+func (p *projectPlugin) ApplyProject(ctx *SchedulerContext) *Status {
+	if ctx.SourceRepoGroups == nil {
+		return nil
+	}
+	for _, sourceGroup := range ctx.SourceRepoGroups.Items {
+		for _, groupRepo := range sourceGroup.Spec.SourceRepositorySpec {
+			if groupRepo.Name != ctx.SourceRepo.Name {
+				continue
+			}
+			name := sourceGroup.Spec.Scheduler.Name
+			if name == "" {
+				continue
+			}
+			scheduler := ctx.Lookup[name]
+			if scheduler == nil {
+				log.Logger().Warnf("A scheduler named %s is referenced by repository group(%s) but could not be found", name, sourceGroup.Name)
+				continue
+			}
+			ctx.Schedulers = append([]*jenkinsv1.SchedulerSpec{&scheduler.Spec}, ctx.Schedulers...)
+		}
+	}
+	return nil
+}
+
+// teamPlugin reimplements addTeamScheduler as an ApplyTeam plugin.
+type teamPlugin struct{}
+
+func (p *teamPlugin) Name() string { return TeamPluginName }
+
+func (p *teamPlugin) ApplyTeam(ctx *SchedulerContext) *Status {
+	defaultScheduler := ctx.Lookup[ctx.TeamSchedulerName]
+	if defaultScheduler != nil {
+		ctx.Schedulers = append([]*jenkinsv1.SchedulerSpec{&defaultScheduler.Spec}, ctx.Schedulers...)
+	} else if ctx.TeamSchedulerName != "" {
+		log.Logger().Warnf("A team pipeline scheduler named %s was configured but could not be found", ctx.TeamSchedulerName)
+	}
+	return nil
+}
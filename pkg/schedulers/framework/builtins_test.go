@@ -0,0 +1,64 @@
+package framework
+
+import (
+	"testing"
+
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+)
+
+// TestDefaultEnabledMergeOrder verifies the built-in plugins still
+// prepend schedulers in the same precedence GenerateProw has always
+// used: repository scheduler applied first (and so pushed furthest back,
+// most specific) and team scheduler applied last (and so ending up at
+// the front, least specific).
+func TestDefaultEnabledMergeOrder(t *testing.T) {
+	teamScheduler := &jenkinsv1.Scheduler{Spec: jenkinsv1.SchedulerSpec{}}
+	repoScheduler := &jenkinsv1.Scheduler{Spec: jenkinsv1.SchedulerSpec{}}
+
+	sourceRepo := jenkinsv1.SourceRepository{}
+	sourceRepo.Name = "repo-resource"
+	sourceRepo.Spec.Org = "org"
+	sourceRepo.Spec.Repo = "repo"
+	sourceRepo.Spec.Scheduler.Name = "repo-scheduler"
+
+	profile := SchedulerProfile{Enabled: []string{
+		ConfigUpdaterPluginName,
+		RepositoryPluginName,
+		ProjectPluginName,
+		TeamPluginName,
+	}}
+	pipeline, err := NewPipeline(DefaultRegistry(), profile)
+	if err != nil {
+		t.Fatalf("NewPipeline: %v", err)
+	}
+
+	ctx := &SchedulerContext{
+		TeamSchedulerName: "team-scheduler",
+		Lookup: map[string]*jenkinsv1.Scheduler{
+			"team-scheduler": teamScheduler,
+			"repo-scheduler": repoScheduler,
+		},
+		SourceRepo: sourceRepo,
+		Schedulers: []*jenkinsv1.SchedulerSpec{},
+	}
+
+	if err := pipeline.RunApplyRepository(ctx); err != nil {
+		t.Fatalf("RunApplyRepository: %v", err)
+	}
+	if err := pipeline.RunApplyProject(ctx); err != nil {
+		t.Fatalf("RunApplyProject: %v", err)
+	}
+	if err := pipeline.RunApplyTeam(ctx); err != nil {
+		t.Fatalf("RunApplyTeam: %v", err)
+	}
+
+	if len(ctx.Schedulers) != 2 {
+		t.Fatalf("got %d schedulers, want 2: %+v", len(ctx.Schedulers), ctx.Schedulers)
+	}
+	if ctx.Schedulers[0] != &teamScheduler.Spec {
+		t.Errorf("schedulers[0] = %p, want team scheduler %p (team is least specific, applied last)", ctx.Schedulers[0], &teamScheduler.Spec)
+	}
+	if ctx.Schedulers[1] != &repoScheduler.Spec {
+		t.Errorf("schedulers[1] = %p, want repository scheduler %p (repository is most specific, applied first)", ctx.Schedulers[1], &repoScheduler.Spec)
+	}
+}
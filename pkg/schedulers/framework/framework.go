@@ -0,0 +1,120 @@
+// Package framework provides a Kubernetes-scheduler-framework-style
+// pipeline for merging pipeline schedulers into a prow config. It lets
+// external code register plugins against named extension points instead
+// of forking GenerateProw.
+package framework
+
+import (
+	"sync"
+
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/lighthouse/pkg/prow/config"
+	"github.com/jenkins-x/lighthouse/pkg/prow/plugins"
+	"github.com/pkg/errors"
+)
+
+// Code is the outcome of running a plugin at an extension point.
+type Code int
+
+const (
+	// Success means the extension point completed normally.
+	Success Code = iota
+	// Skip means the plugin asked for the current repo to be excluded
+	// from scheduler merging, analogous to PreFilter rejecting a node.
+	// Only RunPreFilter acts on it; the other Run* helpers treat a Skip
+	// status like Success.
+	Skip
+	// Error means the extension point failed and the repo should abort.
+	Error
+)
+
+// Status is returned by every extension point, mirroring
+// k8s.io/kubernetes/pkg/scheduler/framework's Status type.
+type Status struct {
+	Code    Code
+	Message string
+}
+
+// NewStatus builds a Status with the given code and message.
+func NewStatus(code Code, message string) *Status {
+	return &Status{Code: code, Message: message}
+}
+
+// IsSuccess reports whether the status represents a successful run. A nil
+// Status is treated as success so plugins can return nil on the happy path.
+func (s *Status) IsSuccess() bool {
+	return s == nil || s.Code == Success
+}
+
+// IsSkip reports whether the status asked for the repo to be skipped.
+func (s *Status) IsSkip() bool {
+	return s != nil && s.Code == Skip
+}
+
+// AsError converts an Error status into a Go error, or nil otherwise.
+func (s *Status) AsError() error {
+	if s == nil || s.Code != Error {
+		return nil
+	}
+	return errors.New(s.Message)
+}
+
+// CycleState carries data between extension points for a single repo's
+// pass through the pipeline, similar to framework.CycleState in
+// kube-scheduler. It is safe for concurrent use.
+type CycleState struct {
+	mu   sync.RWMutex
+	data map[string]interface{}
+}
+
+// NewCycleState returns an empty CycleState.
+func NewCycleState() *CycleState {
+	return &CycleState{data: make(map[string]interface{})}
+}
+
+// Read returns the value stored under key, if any.
+func (c *CycleState) Read(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.data[key]
+	return v, ok
+}
+
+// Write stores value under key, overwriting any previous value.
+func (c *CycleState) Write(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = value
+}
+
+// SchedulerContext is the shared state threaded through every extension
+// point for a single GenerateProw run.
+type SchedulerContext struct {
+	Namespace              string
+	GitOps                 bool
+	AutoApplyConfigUpdater bool
+	TeamSchedulerName      string
+	DevEnv                 *jenkinsv1.Environment
+
+	// Lookup, SourceRepoGroups and SourceRepos are populated once, from
+	// GenerateProw's loadSchedulerResourcesFunc, and shared across every
+	// repo in the run.
+	Lookup           map[string]*jenkinsv1.Scheduler
+	SourceRepoGroups *jenkinsv1.SourceRepositoryGroupList
+	SourceRepos      *jenkinsv1.SourceRepositoryList
+
+	// SourceRepo and Schedulers are reset for each repo processed by the
+	// PreFilter/ApplyTeam/ApplyProject/ApplyRepository/Merge points.
+	SourceRepo jenkinsv1.SourceRepository
+	Schedulers []*jenkinsv1.SchedulerSpec
+	Merged     *jenkinsv1.SchedulerSpec
+
+	// Config and Plugins are populated once, after every repo has been
+	// merged, for the PostMerge and EmitConfig points.
+	Config  *config.Config
+	Plugins *plugins.Configuration
+
+	// State is fresh per repo cycle so plugins can pass data between
+	// their own extension points without polluting SchedulerContext.
+	State *CycleState
+}
@@ -0,0 +1,229 @@
+package framework
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Plugin is implemented by anything registered in a Registry. Concrete
+// plugins additionally implement one or more of the extension-point
+// interfaces below; a plugin that has nothing to say at a given point
+// simply doesn't implement it, so Run* helpers skip it.
+//
+// There is no LoadResources extension point here: loading the team's
+// schedulers/SourceRepositoryGroups/SourceRepositories is already
+// pluggable through GenerateProw's loadSchedulerResourcesFunc parameter,
+// so a second mechanism for the same thing would just be dead weight.
+type Plugin interface {
+	// Name is the unique identifier used to enable the plugin in a
+	// SchedulerProfile.
+	Name() string
+}
+
+// PreFilterPlugin can exclude a repo from scheduler merging before any
+// scheduler is applied to it, analogous to kube-scheduler's PreFilter.
+type PreFilterPlugin interface {
+	Plugin
+	PreFilter(ctx *SchedulerContext) *Status
+}
+
+// ApplyTeamPlugin contributes the team-wide default scheduler.
+type ApplyTeamPlugin interface {
+	Plugin
+	ApplyTeam(ctx *SchedulerContext) *Status
+}
+
+// ApplyProjectPlugin contributes schedulers sourced from
+// SourceRepositoryGroups the repo belongs to.
+type ApplyProjectPlugin interface {
+	Plugin
+	ApplyProject(ctx *SchedulerContext) *Status
+}
+
+// ApplyRepositoryPlugin contributes the scheduler referenced directly by
+// the repo.
+type ApplyRepositoryPlugin interface {
+	Plugin
+	ApplyRepository(ctx *SchedulerContext) *Status
+}
+
+// MergePlugin merges ctx.Schedulers into ctx.Merged.
+type MergePlugin interface {
+	Plugin
+	Merge(ctx *SchedulerContext) *Status
+}
+
+// PostMergePlugin can inspect or mutate the merged SchedulerSpec for a
+// repo after Merge has run, before EmitConfig folds it into the prow
+// config.
+type PostMergePlugin interface {
+	Plugin
+	PostMerge(ctx *SchedulerContext) *Status
+}
+
+// EmitConfigPlugin transforms the final prow config.Config/
+// plugins.Configuration after every repo has been merged into it.
+type EmitConfigPlugin interface {
+	Plugin
+	EmitConfig(ctx *SchedulerContext) *Status
+}
+
+// Factory builds a Plugin from its per-plugin args, mirroring
+// KubeSchedulerProfile's PluginConfig.Args.
+type Factory func(args map[string]interface{}) (Plugin, error)
+
+// Registry maps a plugin name to the factory that constructs it.
+type Registry map[string]Factory
+
+// Register adds a factory under name, returning an error if the name is
+// already taken.
+func (r Registry) Register(name string, factory Factory) error {
+	if _, exists := r[name]; exists {
+		return errors.Errorf("a plugin named %s is already registered", name)
+	}
+	r[name] = factory
+	return nil
+}
+
+// PluginConfig is the per-plugin configuration declared by a
+// SchedulerProfile, mirroring kube-scheduler's PluginConfig.
+type PluginConfig struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// SchedulerProfile declares which plugins are enabled for a run and their
+// args, analogous to KubeSchedulerProfile.
+type SchedulerProfile struct {
+	Enabled []string       `json:"enabled"`
+	Config  []PluginConfig `json:"pluginConfig,omitempty"`
+}
+
+// NewPipeline instantiates every plugin named in profile.Enabled from
+// registry, in order, passing each its configured args.
+func NewPipeline(registry Registry, profile SchedulerProfile) (*Pipeline, error) {
+	args := make(map[string]map[string]interface{}, len(profile.Config))
+	for _, c := range profile.Config {
+		args[c.Name] = c.Args
+	}
+	p := &Pipeline{}
+	for _, name := range profile.Enabled {
+		factory, ok := registry[name]
+		if !ok {
+			return nil, errors.Errorf("no plugin registered with name %s", name)
+		}
+		plugin, err := factory(args[name])
+		if err != nil {
+			return nil, errors.Wrapf(err, "constructing plugin %s", name)
+		}
+		p.plugins = append(p.plugins, plugin)
+	}
+	return p, nil
+}
+
+// Pipeline is an ordered, instantiated set of plugins ready to run against
+// a SchedulerContext.
+type Pipeline struct {
+	plugins []Plugin
+}
+
+// RunPreFilter runs PreFilter on every plugin that implements it. It
+// returns skip=true if any plugin asked for the repo to be skipped.
+func (p *Pipeline) RunPreFilter(ctx *SchedulerContext) (skip bool, err error) {
+	for _, plugin := range p.plugins {
+		pf, ok := plugin.(PreFilterPlugin)
+		if !ok {
+			continue
+		}
+		status := pf.PreFilter(ctx)
+		if status.IsSkip() {
+			return true, nil
+		}
+		if !status.IsSuccess() {
+			return false, errors.Wrapf(status.AsError(), "plugin %s", plugin.Name())
+		}
+	}
+	return false, nil
+}
+
+// RunApplyTeam runs ApplyTeam on every plugin that implements it.
+func (p *Pipeline) RunApplyTeam(ctx *SchedulerContext) error {
+	return p.runSimple(ctx, func(plugin Plugin) (*Status, bool) {
+		at, ok := plugin.(ApplyTeamPlugin)
+		if !ok {
+			return nil, false
+		}
+		return at.ApplyTeam(ctx), true
+	})
+}
+
+// RunApplyProject runs ApplyProject on every plugin that implements it.
+func (p *Pipeline) RunApplyProject(ctx *SchedulerContext) error {
+	return p.runSimple(ctx, func(plugin Plugin) (*Status, bool) {
+		ap, ok := plugin.(ApplyProjectPlugin)
+		if !ok {
+			return nil, false
+		}
+		return ap.ApplyProject(ctx), true
+	})
+}
+
+// RunApplyRepository runs ApplyRepository on every plugin that implements
+// it.
+func (p *Pipeline) RunApplyRepository(ctx *SchedulerContext) error {
+	return p.runSimple(ctx, func(plugin Plugin) (*Status, bool) {
+		ar, ok := plugin.(ApplyRepositoryPlugin)
+		if !ok {
+			return nil, false
+		}
+		return ar.ApplyRepository(ctx), true
+	})
+}
+
+// RunMerge runs Merge on every plugin that implements it. In practice a
+// single built-in plugin handles merging, but the pipeline allows more
+// than one to be registered (e.g. to support an alternative merge
+// strategy behind a profile flag).
+func (p *Pipeline) RunMerge(ctx *SchedulerContext) error {
+	return p.runSimple(ctx, func(plugin Plugin) (*Status, bool) {
+		m, ok := plugin.(MergePlugin)
+		if !ok {
+			return nil, false
+		}
+		return m.Merge(ctx), true
+	})
+}
+
+// RunPostMerge runs PostMerge on every plugin that implements it.
+func (p *Pipeline) RunPostMerge(ctx *SchedulerContext) error {
+	return p.runSimple(ctx, func(plugin Plugin) (*Status, bool) {
+		pm, ok := plugin.(PostMergePlugin)
+		if !ok {
+			return nil, false
+		}
+		return pm.PostMerge(ctx), true
+	})
+}
+
+// RunEmitConfig runs EmitConfig on every plugin that implements it.
+func (p *Pipeline) RunEmitConfig(ctx *SchedulerContext) error {
+	return p.runSimple(ctx, func(plugin Plugin) (*Status, bool) {
+		ec, ok := plugin.(EmitConfigPlugin)
+		if !ok {
+			return nil, false
+		}
+		return ec.EmitConfig(ctx), true
+	})
+}
+
+func (p *Pipeline) runSimple(ctx *SchedulerContext, run func(Plugin) (*Status, bool)) error {
+	for _, plugin := range p.plugins {
+		status, handled := run(plugin)
+		if !handled || status.IsSkip() {
+			continue
+		}
+		if !status.IsSuccess() {
+			return errors.Wrapf(status.AsError(), "plugin %s", plugin.Name())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package framework
+
+import (
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// LoadProfile parses a SchedulerProfile from YAML or JSON, e.g. as stored
+// in a ConfigMap or alongside the team's Scheduler CRDs, mirroring
+// KubeSchedulerProfile's own file-based config.
+func LoadProfile(data []byte) (SchedulerProfile, error) {
+	var profile SchedulerProfile
+	if err := yaml.Unmarshal(data, &profile); err != nil {
+		return SchedulerProfile{}, errors.Wrapf(err, "unmarshaling scheduler profile")
+	}
+	return profile, nil
+}
+
+// LoadProfileFile reads and parses a SchedulerProfile from path.
+func LoadProfileFile(path string) (SchedulerProfile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return SchedulerProfile{}, errors.Wrapf(err, "reading scheduler profile %s", path)
+	}
+	return LoadProfile(data)
+}
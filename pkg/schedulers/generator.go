@@ -1,6 +1,7 @@
 package schedulers
 
 import (
+	"context"
 	"io/ioutil"
 	"strings"
 
@@ -11,12 +12,85 @@ import (
 	"github.com/jenkins-x/jx/pkg/client/clientset/versioned"
 	"github.com/jenkins-x/lighthouse/pkg/prow/config"
 	"github.com/jenkins-x/lighthouse/pkg/prow/plugins"
+	"github.com/jenkins-x/lighthouse/pkg/schedulers/bundle"
+	"github.com/jenkins-x/lighthouse/pkg/schedulers/framework"
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// defaultProfile wires up the built-in plugins in the order GenerateProw
+// has always applied them. Callers who need org-specific behavior should
+// call SetProfile or LoadProfileFile with their own framework.SchedulerProfile
+// instead of forking GenerateProw.
+var defaultProfile = framework.SchedulerProfile{Enabled: append([]string{}, framework.DefaultEnabled...)}
+
+// SetProfile overrides the SchedulerProfile GenerateProw runs, letting
+// external code enable org-specific plugins - or disable/replace
+// built-ins such as the merge step - without forking GenerateProw.
+func SetProfile(profile framework.SchedulerProfile) {
+	defaultProfile = profile
+}
+
+// LoadProfileFile loads a SchedulerProfile from a YAML/JSON file (e.g. a
+// ConfigMap mounted alongside the team's Scheduler CRDs) and makes it the
+// profile GenerateProw runs.
+func LoadProfileFile(path string) error {
+	profile, err := framework.LoadProfileFile(path)
+	if err != nil {
+		return err
+	}
+	SetProfile(profile)
+	return nil
+}
+
+// defaultRegistry returns framework.DefaultRegistry() plus the
+// build-merge plugin: the merge step needs Build, which lives in this
+// package, so framework can't construct it itself without an import
+// cycle.
+func defaultRegistry() framework.Registry {
+	registry := framework.DefaultRegistry()
+	// DefaultRegistry never registers this name itself, so the only way
+	// this can fail is a future built-in plugin colliding with it.
+	if err := registry.Register(framework.MergePluginName, func(map[string]interface{}) (framework.Plugin, error) {
+		return &buildMergePlugin{}, nil
+	}); err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+// buildMergePlugin is the built-in MergePlugin: it calls Build, the
+// merge implementation GenerateProw has always used. Because it is a
+// plugin like any other, a SchedulerProfile can swap it out for a
+// different merge strategy without forking GenerateProw.
+type buildMergePlugin struct{}
+
+func (p *buildMergePlugin) Name() string { return framework.MergePluginName }
+
+func (p *buildMergePlugin) Merge(ctx *framework.SchedulerContext) *framework.Status {
+	merged, err := Build(ctx.Schedulers)
+	if err != nil {
+		return framework.NewStatus(framework.Error, errors.Wrapf(err, "building scheduler").Error())
+	}
+	ctx.Merged = merged
+	return nil
+}
+
 // GenerateProw will generate the prow config for the namespace
 func GenerateProw(gitOps bool, autoApplyConfigUpdater bool, jxClient versioned.Interface, namespace string, teamSchedulerName string, devEnv *jenkinsv1.Environment, loadSchedulerResourcesFunc func(versioned.Interface, string) (map[string]*jenkinsv1.Scheduler, *jenkinsv1.SourceRepositoryGroupList, *jenkinsv1.SourceRepositoryList, error)) (*config.Config,
+	*plugins.Configuration, error) {
+	pipeline, err := framework.NewPipeline(defaultRegistry(), defaultProfile)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "building default scheduler pipeline")
+	}
+	return generate(gitOps, autoApplyConfigUpdater, jxClient, namespace, teamSchedulerName, devEnv, loadSchedulerResourcesFunc, pipeline)
+}
+
+// generate runs pipeline's extension points over every source repo in
+// namespace, merging the schedulers each plugin contributes into a single
+// prow config.Config/plugins.Configuration. It is the pluggable core that
+// GenerateProw configures with the built-in plugins.
+func generate(gitOps bool, autoApplyConfigUpdater bool, jxClient versioned.Interface, namespace string, teamSchedulerName string, devEnv *jenkinsv1.Environment, loadSchedulerResourcesFunc func(versioned.Interface, string) (map[string]*jenkinsv1.Scheduler, *jenkinsv1.SourceRepositoryGroupList, *jenkinsv1.SourceRepositoryList, error), pipeline *framework.Pipeline) (*config.Config,
 	*plugins.Configuration, error) {
 	if loadSchedulerResourcesFunc == nil {
 		loadSchedulerResourcesFunc = loadSchedulerResources
@@ -28,33 +102,56 @@ func GenerateProw(gitOps bool, autoApplyConfigUpdater bool, jxClient versioned.I
 	if sourceRepos == nil || len(sourceRepos.Items) < 1 {
 		return nil, nil, errors.New("No source repository resources were found")
 	}
-	defaultScheduler := schedulers[teamSchedulerName]
+	ctx := &framework.SchedulerContext{
+		Namespace:              namespace,
+		GitOps:                 gitOps,
+		AutoApplyConfigUpdater: autoApplyConfigUpdater,
+		TeamSchedulerName:      teamSchedulerName,
+		DevEnv:                 devEnv,
+		Lookup:                 schedulers,
+		SourceRepoGroups:       sourceRepoGroups,
+		SourceRepos:            sourceRepos,
+	}
 	leaves := make([]*SchedulerLeaf, 0)
 	for _, sourceRepo := range sourceRepos.Items {
-		applicableSchedulers := []*jenkinsv1.SchedulerSpec{}
-		// Apply config-updater to devEnv
-		applicableSchedulers = addConfigUpdaterToDevEnv(gitOps, autoApplyConfigUpdater, applicableSchedulers, devEnv, &sourceRepo.Spec)
-		// Apply repo scheduler
-		applicableSchedulers = addRepositoryScheduler(sourceRepo, schedulers, applicableSchedulers)
+		ctx.SourceRepo = sourceRepo
+		ctx.Schedulers = []*jenkinsv1.SchedulerSpec{}
+		ctx.State = framework.NewCycleState()
+
+		skip, err := pipeline.RunPreFilter(ctx)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "pre-filtering repo %s/%s", sourceRepo.Spec.Org, sourceRepo.Spec.Repo)
+		}
+		if skip {
+			continue
+		}
+		// Apply repo scheduler (and config-updater, which also targets
+		// the repo's own scheduler slot)
+		if err := pipeline.RunApplyRepository(ctx); err != nil {
+			return nil, nil, err
+		}
 		// Apply project schedulers
-		applicableSchedulers = addProjectSchedulers(sourceRepoGroups, sourceRepo, schedulers, applicableSchedulers)
+		if err := pipeline.RunApplyProject(ctx); err != nil {
+			return nil, nil, err
+		}
 		// Apply team scheduler
-		applicableSchedulers = addTeamScheduler(teamSchedulerName, defaultScheduler, applicableSchedulers)
-		if len(applicableSchedulers) < 1 {
+		if err := pipeline.RunApplyTeam(ctx); err != nil {
+			return nil, nil, err
+		}
+		if len(ctx.Schedulers) < 1 {
 			continue
 		}
-		merged, err := Build(applicableSchedulers)
-		if err != nil {
-			return nil, nil, errors.Wrapf(err, "building scheduler")
+		if err := pipeline.RunMerge(ctx); err != nil {
+			return nil, nil, err
+		}
+		if err := pipeline.RunPostMerge(ctx); err != nil {
+			return nil, nil, err
 		}
 		leaves = append(leaves, &SchedulerLeaf{
 			Repo:          sourceRepo.Spec.Repo,
 			Org:           sourceRepo.Spec.Org,
-			SchedulerSpec: merged,
+			SchedulerSpec: ctx.Merged,
 		})
-		if err != nil {
-			return nil, nil, errors.Wrapf(err, "building prow config")
-		}
 	}
 	cfg, plugs, err := BuildProwConfig(leaves)
 	if err != nil {
@@ -64,7 +161,71 @@ func GenerateProw(gitOps bool, autoApplyConfigUpdater bool, jxClient versioned.I
 		cfg.PodNamespace = namespace
 		//cfg.ProwJobNamespace = namespace
 	}
-	return cfg, plugs, nil
+	ctx.Config = cfg
+	ctx.Plugins = plugs
+	if err := pipeline.RunEmitConfig(ctx); err != nil {
+		return nil, nil, err
+	}
+	return ctx.Config, ctx.Plugins, nil
+}
+
+// EffectiveScheduler computes the merged SchedulerSpec and resulting prow
+// config fragment for a single repo, running the same extension-point
+// chain GenerateProw runs for every repo. It returns nil results (and a
+// nil error) if no plugin contributed a scheduler for the repo, mirroring
+// GenerateProw skipping such repos entirely. It is used by the live
+// scheduler API to answer "what does this repo's effective config look
+// like".
+func EffectiveScheduler(gitOps bool, autoApplyConfigUpdater bool, teamSchedulerName string, devEnv *jenkinsv1.Environment, lookup map[string]*jenkinsv1.Scheduler, sourceRepoGroups *jenkinsv1.SourceRepositoryGroupList, sourceRepo jenkinsv1.SourceRepository) (*jenkinsv1.SchedulerSpec, *config.Config, *plugins.Configuration, error) {
+	pipeline, err := framework.NewPipeline(defaultRegistry(), defaultProfile)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "building default scheduler pipeline")
+	}
+	ctx := &framework.SchedulerContext{
+		GitOps:                 gitOps,
+		AutoApplyConfigUpdater: autoApplyConfigUpdater,
+		TeamSchedulerName:      teamSchedulerName,
+		DevEnv:                 devEnv,
+		Lookup:                 lookup,
+		SourceRepoGroups:       sourceRepoGroups,
+		SourceRepo:             sourceRepo,
+		Schedulers:             []*jenkinsv1.SchedulerSpec{},
+		State:                  framework.NewCycleState(),
+	}
+	skip, err := pipeline.RunPreFilter(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if skip {
+		return nil, nil, nil, nil
+	}
+	if err := pipeline.RunApplyRepository(ctx); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := pipeline.RunApplyProject(ctx); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := pipeline.RunApplyTeam(ctx); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(ctx.Schedulers) < 1 {
+		return nil, nil, nil, nil
+	}
+	if err := pipeline.RunMerge(ctx); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := pipeline.RunPostMerge(ctx); err != nil {
+		return nil, nil, nil, err
+	}
+	cfg, plugs, err := BuildProwConfig([]*SchedulerLeaf{{
+		Repo:          sourceRepo.Spec.Repo,
+		Org:           sourceRepo.Spec.Org,
+		SchedulerSpec: ctx.Merged,
+	}})
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "building prow config")
+	}
+	return ctx.Merged, cfg, plugs, nil
 }
 
 func loadSchedulerResources(jxClient versioned.Interface, namespace string) (map[string]*jenkinsv1.Scheduler, *jenkinsv1.SourceRepositoryGroupList, *jenkinsv1.SourceRepositoryList, error) {
@@ -89,9 +250,75 @@ func loadSchedulerResources(jxClient versioned.Interface, namespace string) (map
 	if err != nil {
 		return nil, nil, nil, errors.Wrapf(err, "Error finding source repositories")
 	}
+	if err := resolveBundleRefs(lookup, sourceRepoGroups, sourceRepos); err != nil {
+		return nil, nil, nil, err
+	}
 	return lookup, sourceRepoGroups, sourceRepos, nil
 }
 
+// bundleResolver, if set via SetBundleResolver, resolves
+// "oci://registry/name@sha256:..." scheduler references into installed
+// jenkinsv1.Scheduler resources. It is nil by default so deployments that
+// never use bundles pay no cost and need no registry to reach.
+var bundleResolver *bundle.Resolver
+
+// SetBundleResolver configures the resolver used to install scheduler
+// bundles referenced by SourceRepository/SourceRepositoryGroup scheduler
+// names of the form oci://registry/name@sha256:digest.
+func SetBundleResolver(resolver *bundle.Resolver) {
+	bundleResolver = resolver
+}
+
+// ResolveBundleRefs scans every scheduler name referenced by
+// sourceRepoGroups and sourceRepos for a bundle reference, pulling and
+// digest-verifying it into lookup under that exact reference string so
+// the built-in repository/project plugins find it without any further
+// changes. It is exported so callers with their own
+// loadSchedulerResourcesFunc - such as the scheduler API's
+// loadResourcesExcludingPaused - can resolve bundles the same way
+// loadSchedulerResources does.
+func ResolveBundleRefs(lookup map[string]*jenkinsv1.Scheduler, sourceRepoGroups *jenkinsv1.SourceRepositoryGroupList, sourceRepos *jenkinsv1.SourceRepositoryList) error {
+	return resolveBundleRefs(lookup, sourceRepoGroups, sourceRepos)
+}
+
+// resolveBundleRefs is ResolveBundleRefs' implementation; see there.
+func resolveBundleRefs(lookup map[string]*jenkinsv1.Scheduler, sourceRepoGroups *jenkinsv1.SourceRepositoryGroupList, sourceRepos *jenkinsv1.SourceRepositoryList) error {
+	refs := map[string]bool{}
+	if sourceRepoGroups != nil {
+		for _, group := range sourceRepoGroups.Items {
+			if name := group.Spec.Scheduler.Name; bundle.IsRef(name) {
+				refs[name] = true
+			}
+		}
+	}
+	if sourceRepos != nil {
+		for _, repo := range sourceRepos.Items {
+			if name := repo.Spec.Scheduler.Name; bundle.IsRef(name) {
+				refs[name] = true
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	if bundleResolver == nil {
+		for ref := range refs {
+			log.Logger().Warnf("A scheduler bundle %s is referenced but no bundle resolver is configured", ref)
+		}
+		return nil
+	}
+	for ref := range refs {
+		// The install key is always ref itself, since that's the exact
+		// string referencing repos/groups look up in lookup.
+		_, scheduler, err := bundleResolver.Resolve(context.Background(), ref, "")
+		if err != nil {
+			return errors.Wrapf(err, "resolving scheduler bundle %s", ref)
+		}
+		lookup[ref] = scheduler
+	}
+	return nil
+}
+
 //cleanupExistingProwConfig Removes config that we do not currently support
 func cleanupExistingProwConfig(prowConfig *config.Config, pluginConfig *plugins.Configuration, sourceRepoMap map[string]*jenkinsv1.SourceRepository) {
 	// Deck is not supported
@@ -225,67 +452,8 @@ func dumpProwConfigToFiles(prefix string, prowConfig *config.Config, pluginConfi
 	log.Logger().Infof("Writing migrated plugins to %s", migratedPluginsFile)
 }
 
-func addTeamScheduler(defaultSchedulerName string, defaultScheduler *jenkinsv1.Scheduler, applicableSchedulers []*jenkinsv1.SchedulerSpec) []*jenkinsv1.SchedulerSpec {
-	if defaultScheduler != nil {
-		applicableSchedulers = append([]*jenkinsv1.SchedulerSpec{&defaultScheduler.Spec}, applicableSchedulers...)
-	} else {
-		if defaultSchedulerName != "" {
-			log.Logger().Warnf("A team pipeline scheduler named %s was configured but could not be found", defaultSchedulerName)
-		}
-	}
-	return applicableSchedulers
-}
-
-func addRepositoryScheduler(sourceRepo jenkinsv1.SourceRepository, lookup map[string]*jenkinsv1.Scheduler, applicableSchedulers []*jenkinsv1.SchedulerSpec) []*jenkinsv1.SchedulerSpec {
-	if sourceRepo.Spec.Scheduler.Name != "" {
-		scheduler := lookup[sourceRepo.Spec.Scheduler.Name]
-		if scheduler != nil {
-			applicableSchedulers = append([]*jenkinsv1.SchedulerSpec{&scheduler.Spec}, applicableSchedulers...)
-		} else {
-			log.Logger().Warnf("A scheduler named %s is referenced by repository(%s) but could not be found", sourceRepo.Spec.Scheduler.Name, sourceRepo.Name)
-		}
-	}
-	return applicableSchedulers
-}
-
-func addProjectSchedulers(sourceRepoGroups *jenkinsv1.SourceRepositoryGroupList, sourceRepo jenkinsv1.SourceRepository, lookup map[string]*jenkinsv1.Scheduler, applicableSchedulers []*jenkinsv1.SchedulerSpec) []*jenkinsv1.SchedulerSpec {
-	if sourceRepoGroups != nil {
-		for _, sourceGroup := range sourceRepoGroups.Items {
-			for _, groupRepo := range sourceGroup.Spec.SourceRepositorySpec {
-				if groupRepo.Name == sourceRepo.Name {
-					if sourceGroup.Spec.Scheduler.Name != "" {
-						scheduler := lookup[sourceGroup.Spec.Scheduler.Name]
-						if scheduler != nil {
-							applicableSchedulers = append([]*jenkinsv1.SchedulerSpec{&scheduler.Spec}, applicableSchedulers...)
-						} else {
-							log.Logger().Warnf("A scheduler named %s is referenced by repository group(%s) but could not be found", sourceGroup.Spec.Scheduler.Name, sourceGroup.Name)
-						}
-					}
-				}
-			}
-		}
-	}
-	return applicableSchedulers
-}
-
-func addConfigUpdaterToDevEnv(gitOps bool, autoApplyConfigUpdater bool, applicableSchedulers []*jenkinsv1.SchedulerSpec, devEnv *jenkinsv1.Environment, sourceRepo *jenkinsv1.SourceRepositorySpec) []*jenkinsv1.SchedulerSpec {
-	if gitOps && autoApplyConfigUpdater && strings.Contains(devEnv.Spec.Source.URL, sourceRepo.Org+"/"+sourceRepo.Repo) {
-		maps := make(map[string]jenkinsv1.ConfigMapSpec)
-		maps["env/prow/config.yaml"] = jenkinsv1.ConfigMapSpec{
-			Name: "config",
-		}
-		maps["env/prow/plugins.yaml"] = jenkinsv1.ConfigMapSpec{
-			Name: "plugins",
-		}
-		environmentUpdaterSpec := &jenkinsv1.SchedulerSpec{
-			ConfigUpdater: &jenkinsv1.ConfigUpdater{
-				Map: maps,
-			},
-			Plugins: &jenkinsv1.ReplaceableSliceOfStrings{
-				Items: []string{"config-updater"},
-			},
-		}
-		applicableSchedulers = append([]*jenkinsv1.SchedulerSpec{environmentUpdaterSpec}, applicableSchedulers...)
-	}
-	return applicableSchedulers
-}
+// addTeamScheduler, addRepositoryScheduler, addProjectSchedulers and
+// addConfigUpdaterToDevEnv used to live here; they are now the built-in
+// framework.TeamPluginName, framework.RepositoryPluginName,
+// framework.ProjectPluginName and framework.ConfigUpdaterPluginName
+// plugins in pkg/schedulers/framework, registered by DefaultRegistry.
@@ -0,0 +1,215 @@
+package schedulers
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/jx/pkg/client/clientset/versioned"
+	"github.com/jenkins-x/lighthouse/pkg/prow/config"
+	"github.com/jenkins-x/lighthouse/pkg/schedulers/validation"
+	"github.com/pkg/errors"
+)
+
+// ValidationResult collects the diagnostics Validate produces in a single
+// pass, instead of GenerateProw's fail-fast behavior.
+//
+// Provenance below is scheduler-level only (see its own doc): Build's
+// merge records no per-field origin, so no SchedulerSpec field can be
+// traced back to the single scheduler that set it.
+type ValidationResult struct {
+	Errors validation.ErrorList
+	// Provenance maps "org/repo" to the scheduler names that
+	// contributed to its merged SchedulerSpec, outermost
+	// (highest-precedence) first.
+	//
+	// This is scheduler-level attribution only: it answers "which
+	// schedulers applied to this repo", not "which scheduler set this
+	// specific field". Narrowing an ambiguous trigger down to the one
+	// scheduler responsible still needs a manual diff across
+	// Provenance's entries.
+	Provenance map[string][]string
+}
+
+// HasErrors reports whether Validate found any diagnostic.
+func (r *ValidationResult) HasErrors() bool {
+	return len(r.Errors) > 0
+}
+
+// Validate runs the same scheduler-to-repo precedence GenerateProw uses,
+// collecting structured diagnostics instead of failing at the first
+// problem it finds: unresolvable scheduler references (which
+// addRepositoryScheduler/addProjectSchedulers - now the repository/project
+// plugins - only log as warnings), config-updater maps two schedulers
+// both claim, tide queries naming repos that have no SourceRepository
+// (which cleanupExistingProwConfig would drop), and org-level
+// branch-protection policies set alongside per-repo overrides.
+//
+// It does not know which team scheduler a run would use, nor whether
+// gitOps config-updater auto-apply is on, so it validates every repo as
+// if no team scheduler and no config-updater auto-apply applied;
+// GenerateProw's own team-level defaulting is not re-validated here.
+func Validate(lookup map[string]*jenkinsv1.Scheduler, sourceRepoGroups *jenkinsv1.SourceRepositoryGroupList, sourceRepos *jenkinsv1.SourceRepositoryList, devEnv *jenkinsv1.Environment) (*ValidationResult, error) {
+	result := &ValidationResult{Provenance: make(map[string][]string)}
+	if sourceRepos == nil {
+		return result, nil
+	}
+	sourceRepoMap := make(map[string]*jenkinsv1.SourceRepository, len(sourceRepos.Items))
+	for i := range sourceRepos.Items {
+		repo := &sourceRepos.Items[i]
+		sourceRepoMap[repo.Spec.Org+"/"+repo.Spec.Repo] = repo
+	}
+
+	if sourceRepoGroups != nil {
+		for _, group := range sourceRepoGroups.Items {
+			name := group.Spec.Scheduler.Name
+			if name == "" || lookup[name] != nil {
+				continue
+			}
+			result.Errors = append(result.Errors, validation.NotFound(
+				fmt.Sprintf("sourceRepositoryGroups[%s].spec.scheduler.name", group.Name), name, name))
+		}
+	}
+	for _, sourceRepo := range sourceRepos.Items {
+		name := sourceRepo.Spec.Scheduler.Name
+		if name == "" || lookup[name] != nil {
+			continue
+		}
+		result.Errors = append(result.Errors, validation.NotFound(
+			fmt.Sprintf("sourceRepositories[%s].spec.scheduler.name", sourceRepo.Name), name, name))
+	}
+
+	// Config-updater map collisions: two schedulers can't both claim the
+	// same repo's env/prow/*.yaml config map without one silently
+	// winning once they're merged.
+	claimedBy := make(map[string]string)
+	for name, scheduler := range lookup {
+		if scheduler.Spec.ConfigUpdater == nil {
+			continue
+		}
+		for path := range scheduler.Spec.ConfigUpdater.Map {
+			if existing, ok := claimedBy[path]; ok && existing != name {
+				result.Errors = append(result.Errors, validation.Duplicate(
+					fmt.Sprintf("schedulers[%s].spec.configUpdater.map[%s]", name, path), name, path))
+				continue
+			}
+			claimedBy[path] = name
+		}
+	}
+
+	for _, sourceRepo := range sourceRepos.Items {
+		key := sourceRepo.Spec.Org + "/" + sourceRepo.Spec.Repo
+		merged, cfg, _, err := EffectiveScheduler(false, false, "", devEnv, lookup, sourceRepoGroups, sourceRepo)
+		if err != nil {
+			result.Errors = append(result.Errors, validation.Invalid(
+				fmt.Sprintf("sourceRepositories[%s]", sourceRepo.Name), sourceRepo.Spec.Scheduler.Name, nil, err.Error()))
+			continue
+		}
+		if merged == nil {
+			continue
+		}
+		result.Provenance[key] = provenanceNames(sourceRepo, sourceRepoGroups)
+		if cfg == nil {
+			continue
+		}
+		for qi, query := range cfg.Tide.Queries {
+			for _, repo := range query.Repos {
+				if sourceRepoMap[repo] == nil {
+					result.Errors = append(result.Errors, validation.NotFound(
+						fmt.Sprintf("repos[%s].tide.queries[%d].repos", key, qi), sourceRepo.Spec.Scheduler.Name, repo))
+				}
+			}
+		}
+		for org, protection := range cfg.BranchProtection.Orgs {
+			if protection.Repos != nil && !isZeroPolicy(protection.Policy) {
+				result.Errors = append(result.Errors, validation.Invalid(
+					fmt.Sprintf("repos[%s].branchProtection.orgs[%s]", key, org), sourceRepo.Spec.Scheduler.Name, nil,
+					"org-level policy is set alongside explicit per-repo overrides"))
+			}
+		}
+	}
+	return result, nil
+}
+
+// provenanceNames lists, in precedence order, the scheduler names
+// EffectiveScheduler would have applied for sourceRepo - excluding
+// config-updater, which Validate never applies since it has no
+// gitOps/auto-apply context to apply it with. It is scheduler-level
+// only; see ValidationResult.Provenance for why this stops short of
+// field-level attribution.
+func provenanceNames(sourceRepo jenkinsv1.SourceRepository, sourceRepoGroups *jenkinsv1.SourceRepositoryGroupList) []string {
+	var names []string
+	if name := sourceRepo.Spec.Scheduler.Name; name != "" {
+		names = append(names, name)
+	}
+	if sourceRepoGroups != nil {
+		for _, group := range sourceRepoGroups.Items {
+			for _, groupRepo := range group.Spec.SourceRepositorySpec {
+				if groupRepo.Name == sourceRepo.Name && group.Spec.Scheduler.Name != "" {
+					names = append(names, group.Spec.Scheduler.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+func isZeroPolicy(policy config.Policy) bool {
+	return reflect.DeepEqual(policy, config.Policy{})
+}
+
+// DryRun runs the same merge pipeline GenerateProw does without writing
+// anything to the cluster: it writes any diagnostics Validate found to
+// out, then renders the resulting config.yaml/plugins.yaml, reusing the
+// same YAML marshaling dumpProwConfigToFiles uses, followed by a per-repo
+// summary of which schedulers contributed to its merged SchedulerSpec -
+// see ValidationResult.Provenance for the scope of that summary.
+func DryRun(jxClient versioned.Interface, namespace string, teamSchedulerName string, devEnv *jenkinsv1.Environment, gitOps bool, autoApplyConfigUpdater bool, out io.Writer) error {
+	lookup, sourceRepoGroups, sourceRepos, err := loadSchedulerResources(jxClient, namespace)
+	if err != nil {
+		return errors.Wrapf(err, "loading scheduler resources")
+	}
+	result, err := Validate(lookup, sourceRepoGroups, sourceRepos, devEnv)
+	if err != nil {
+		return errors.Wrapf(err, "validating scheduler resources")
+	}
+	for _, valErr := range result.Errors {
+		fmt.Fprintf(out, "# %s\n", valErr.Error())
+	}
+	cfg, plugs, err := GenerateProw(gitOps, autoApplyConfigUpdater, jxClient, namespace, teamSchedulerName, devEnv,
+		func(versioned.Interface, string) (map[string]*jenkinsv1.Scheduler, *jenkinsv1.SourceRepositoryGroupList, *jenkinsv1.SourceRepositoryList, error) {
+			return lookup, sourceRepoGroups, sourceRepos, nil
+		})
+	if err != nil {
+		return errors.Wrapf(err, "generating prow config")
+	}
+	cnfBytes, err := yaml.Marshal(cfg)
+	if err != nil {
+		return errors.Wrapf(err, "marshaling prow config to yaml")
+	}
+	if _, err := fmt.Fprintf(out, "# config.yaml\n%s\n", cnfBytes); err != nil {
+		return errors.WithStack(err)
+	}
+	plugsBytes, err := yaml.Marshal(plugs)
+	if err != nil {
+		return errors.Wrapf(err, "marshaling prow plugins config to yaml")
+	}
+	if _, err := fmt.Fprintf(out, "# plugins.yaml\n%s\n", plugsBytes); err != nil {
+		return errors.WithStack(err)
+	}
+
+	fmt.Fprintln(out, "# provenance (schedulers applied, outermost first - not field-level)")
+	keys := make([]string, 0, len(result.Provenance))
+	for key := range result.Provenance {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(out, "#   %s: %s\n", key, strings.Join(result.Provenance[key], " < "))
+	}
+	return nil
+}
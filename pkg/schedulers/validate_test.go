@@ -0,0 +1,38 @@
+package schedulers
+
+import (
+	"testing"
+
+	jenkinsv1 "github.com/jenkins-x/jx/pkg/apis/jenkins.io/v1"
+	"github.com/jenkins-x/lighthouse/pkg/schedulers/validation"
+)
+
+func TestValidateFlagsConfigUpdaterMapCollision(t *testing.T) {
+	configUpdaterMap := func() map[string]jenkinsv1.ConfigMapSpec {
+		return map[string]jenkinsv1.ConfigMapSpec{"env/prow/config.yaml": {Name: "config"}}
+	}
+	lookup := map[string]*jenkinsv1.Scheduler{
+		"scheduler-a": {Spec: jenkinsv1.SchedulerSpec{ConfigUpdater: &jenkinsv1.ConfigUpdater{Map: configUpdaterMap()}}},
+		"scheduler-b": {Spec: jenkinsv1.SchedulerSpec{ConfigUpdater: &jenkinsv1.ConfigUpdater{Map: configUpdaterMap()}}},
+	}
+	sourceRepo := jenkinsv1.SourceRepository{}
+	sourceRepo.Name = "repo-resource"
+	sourceRepo.Spec.Org = "org"
+	sourceRepo.Spec.Repo = "repo"
+	sourceRepos := &jenkinsv1.SourceRepositoryList{Items: []jenkinsv1.SourceRepository{sourceRepo}}
+
+	result, err := Validate(lookup, nil, sourceRepos, nil)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	found := false
+	for _, valErr := range result.Errors {
+		if valErr.Type == validation.ErrorTypeDuplicate {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Validate did not flag the colliding config-updater map entry, got: %+v", result.Errors)
+	}
+}
@@ -0,0 +1,96 @@
+// Package validation provides structured diagnostics for scheduler
+// merging, modeled on k8s.io/kubernetes/pkg/scheduler/apis/config/validation's
+// field.ErrorList: each Error carries a JSON path, an offending scheduler
+// name and one of a small set of error types, instead of a single
+// fail-fast error.
+package validation
+
+import "fmt"
+
+// ErrorType distinguishes the kind of problem an Error reports.
+type ErrorType string
+
+// The set of error types an Error can carry. Validate reports Invalid,
+// Duplicate and NotFound errors; it never produces a Required error.
+const (
+	// ErrorTypeRequired means a value was unset where one is needed.
+	ErrorTypeRequired ErrorType = "Required"
+	// ErrorTypeInvalid means a value is set but not usable as-is.
+	ErrorTypeInvalid ErrorType = "Invalid"
+	// ErrorTypeDuplicate means two schedulers both claim the same
+	// value where only one can win.
+	ErrorTypeDuplicate ErrorType = "Duplicate"
+	// ErrorTypeNotFound means a value references something that
+	// does not exist.
+	ErrorTypeNotFound ErrorType = "NotFound"
+)
+
+// Error is a single diagnostic produced while merging schedulers.
+type Error struct {
+	Type ErrorType
+	// Field is the JSON path of the value the diagnostic concerns, e.g.
+	// "schedulers[my-scheduler].spec.configUpdater.map[env/prow/config.yaml]".
+	Field string
+	// Scheduler is the name of the scheduler responsible for the
+	// offending value, where applicable.
+	Scheduler string
+	// BadValue is the value that triggered the diagnostic.
+	BadValue interface{}
+	// Detail, if set, elaborates on why the value is a problem.
+	Detail string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %s: %v", e.Field, e.Type, e.BadValue)
+	if e.Scheduler != "" {
+		msg = fmt.Sprintf("scheduler %s, %s", e.Scheduler, msg)
+	}
+	if e.Detail != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Detail)
+	}
+	return msg
+}
+
+// Required builds an ErrorTypeRequired Error.
+func Required(field, scheduler, detail string) *Error {
+	return &Error{Type: ErrorTypeRequired, Field: field, Scheduler: scheduler, Detail: detail}
+}
+
+// Invalid builds an ErrorTypeInvalid Error.
+func Invalid(field, scheduler string, badValue interface{}, detail string) *Error {
+	return &Error{Type: ErrorTypeInvalid, Field: field, Scheduler: scheduler, BadValue: badValue, Detail: detail}
+}
+
+// Duplicate builds an ErrorTypeDuplicate Error.
+func Duplicate(field, scheduler string, badValue interface{}) *Error {
+	return &Error{Type: ErrorTypeDuplicate, Field: field, Scheduler: scheduler, BadValue: badValue}
+}
+
+// NotFound builds an ErrorTypeNotFound Error.
+func NotFound(field, scheduler string, badValue interface{}) *Error {
+	return &Error{Type: ErrorTypeNotFound, Field: field, Scheduler: scheduler, BadValue: badValue}
+}
+
+// ErrorList is a collection of diagnostics.
+type ErrorList []*Error
+
+// Error joins every diagnostic's message, one per line.
+func (list ErrorList) Error() string {
+	msg := ""
+	for i, err := range list {
+		if i > 0 {
+			msg += "\n"
+		}
+		msg += err.Error()
+	}
+	return msg
+}
+
+// ToAggregate returns list as an error, or nil if it is empty.
+func (list ErrorList) ToAggregate() error {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}